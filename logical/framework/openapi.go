@@ -1,6 +1,8 @@
 package framework
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
@@ -30,13 +32,46 @@ func NewOASDocument() *OASDocument {
 			},
 		},
 		Paths: make(map[string]*oasPathItem),
+		Components: oasComponents{
+			SecuritySchemes: oasSecuritySchemes{
+				"vaultToken": &oasSecurityScheme{
+					Type: "apiKey",
+					In:   "header",
+					Name: "X-Vault-Token",
+					Description: "Most Vault endpoints require a token supplied via the " +
+						"X-Vault-Token header, obtained by logging in through an auth method " +
+						"or by providing a valid response-wrapping token.",
+				},
+			},
+		},
 	}
 }
 
 type OASDocument struct {
-	Version string                  `json:"openapi"`
-	Info    oasInfo                 `json:"info"`
-	Paths   map[string]*oasPathItem `json:"paths"`
+	Version    string                  `json:"openapi"`
+	Info       oasInfo                 `json:"info"`
+	Paths      map[string]*oasPathItem `json:"paths"`
+	Components oasComponents           `json:"components"`
+}
+
+type oasComponents struct {
+	SecuritySchemes oasSecuritySchemes    `json:"securitySchemes,omitempty"`
+	Schemas         map[string]*oasSchema `json:"schemas,omitempty"`
+}
+
+type oasSecuritySchemes map[string]*oasSecurityScheme
+
+type oasSecurityScheme struct {
+	Type        string `json:"type"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// oasDefaultSecurity is the security requirement applied to every operation
+// that does not opt out via Unauthenticated or an explicit override.
+var oasDefaultSecurity = []map[string][]string{
+	{"vaultToken": {}},
 }
 
 type oasInfo struct {
@@ -78,15 +113,17 @@ type OASOperation struct {
 	RequestBody *oasRequestBody         `json:"requestBody,omitempty"`
 	Responses   map[string]*oasResponse `json:"responses"`
 	Deprecated  bool                    `json:"deprecated,omitempty"`
+	Security    []map[string][]string   `json:"security"`
 }
 
 type oasParameter struct {
-	Name        string     `json:"name"`
-	Description string     `json:"description,omitempty"`
-	In          string     `json:"in"`
-	Schema      *oasSchema `json:"schema,omitempty"`
-	Required    bool       `json:"required,omitempty"`
-	Deprecated  bool       `json:"deprecated,omitempty"`
+	Name         string     `json:"name"`
+	Description  string     `json:"description,omitempty"`
+	In           string     `json:"in"`
+	Schema       *oasSchema `json:"schema,omitempty"`
+	Required     bool       `json:"required,omitempty"`
+	Deprecated   bool       `json:"deprecated,omitempty"`
+	MultiSegment bool       `json:"x-vault-multi-segment,omitempty"`
 }
 
 type oasRequestBody struct {
@@ -101,13 +138,36 @@ type oasMediaTypeObject struct {
 }
 
 type oasSchema struct {
+	Ref         string                `json:"-"`
 	Type        string                `json:"type,omitempty"`
 	Description string                `json:"description,omitempty"`
 	Properties  map[string]*oasSchema `json:"properties,omitempty"`
+	Required    []string              `json:"required,omitempty"`
 	Items       *oasSchema            `json:"items,omitempty"`
 	Format      string                `json:"format,omitempty"`
+	Pattern     string                `json:"pattern,omitempty"`
 	Example     interface{}           `json:"example,omitempty"`
 	Deprecated  bool                  `json:"deprecated,omitempty"`
+	ReadOnly    bool                  `json:"readOnly,omitempty"`
+	WriteOnly   bool                  `json:"writeOnly,omitempty"`
+}
+
+// oasSchemaRef is the wire representation of a schema that only points at a
+// components.schemas entry. It is used by oasSchema's MarshalJSON so that a
+// schema with Ref set serializes as a bare "$ref" rather than inlining its
+// (otherwise unused) fields.
+type oasSchemaRef struct {
+	Ref string `json:"$ref"`
+}
+
+func (s *oasSchema) MarshalJSON() ([]byte, error) {
+	if s.Ref != "" {
+		return json.Marshal(&oasSchemaRef{Ref: s.Ref})
+	}
+
+	// Alias to avoid infinite recursion back into this MarshalJSON.
+	type alias oasSchema
+	return json.Marshal((*alias)(s))
 }
 
 type oasResponse struct {
@@ -127,7 +187,6 @@ var oasStdRespNoContent = &oasResponse{
 // Predefined here to avoid substantial recompilation.
 var reqdRe = regexp.MustCompile(`\(?\?P<(\w+)>[^)]*\)?`) // Capture required parameters, e.g. "(?P<name>regex)"
 var optRe = regexp.MustCompile(`(?U)\(.*\)\?`)           // Capture optional path elements in ungreedy (?U) fashion, e.g. "(leases/)?renew"
-var altRe = regexp.MustCompile(`\((.*)\|(.*)\)`)         // Capture alternation elements, e.g. "(raw/?$|raw/(?P<path>.+))"
 var pathFieldsRe = regexp.MustCompile(`{(\w+)}`)         // Capture OpenAPI-style named parameters, e.g. "lookup/{urltoken}",
 var cleanCharsRe = regexp.MustCompile("[()^$?]")         // Set of regex characters that will be stripped during cleaning
 var cleanSuffixRe = regexp.MustCompile(`/\?\$?$`)        // Path suffix patterns that will be stripped during cleaning
@@ -154,8 +213,9 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 		unauthPaths = specialPaths.Unauthenticated
 	}
 
-	// Convert optional parameters into distinct patterns to be process independently.
-	paths := expandPattern(p.Pattern)
+	// Convert optional parameters, alternations and multi-segment wildcards
+	// into distinct patterns to be processed independently.
+	paths, multiSegment := expandPattern(p.Pattern, p.Fields)
 
 	for _, path := range paths {
 		// Construct a top level PathItem which will be populated as the path is processed.
@@ -187,6 +247,17 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 			location := "path"
 			required := true
 
+			// A PathExamples expansion may introduce a nested {param}
+			// placeholder (e.g. "role/{role_name}") that has no matching
+			// entry in the Path's own Fields; synthesize a bare string
+			// schema for it rather than dereferencing a nil field.
+			if field == nil {
+				field = &FieldSchema{
+					Type:        TypeString,
+					Description: "Path segment supplied by a PathExamples expansion.",
+				}
+			}
+
 			// Header parameters are part of the Parameters group but with
 			// a dedicated "header" location, a header parameter is not required.
 			if field.Type == TypeHeader {
@@ -195,13 +266,26 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 			}
 
 			t := convertType(field.Type)
+			description := cleanString(field.Description)
+
+			// A named parameter whose regex was never expanded into concrete
+			// literal paths still matches multiple path segments, so callers
+			// should be warned it may legitimately contain slashes. Scoped to
+			// this expanded path, since an alternation can reuse the same
+			// field name as an ordinary single-segment parameter elsewhere.
+			multi := multiSegment[path][name]
+			if multi {
+				description = strings.TrimSpace(description + " This parameter may contain slashes and matches multiple path segments.")
+			}
+
 			p := oasParameter{
-				Name:        name,
-				Description: cleanString(field.Description),
-				In:          location,
-				Schema:      &oasSchema{Type: t.baseType},
-				Required:    required,
-				Deprecated:  field.Deprecated,
+				Name:         name,
+				Description:  description,
+				In:           location,
+				Schema:       &oasSchema{Type: t.baseType},
+				Required:     required,
+				Deprecated:   field.Deprecated,
+				MultiSegment: multi,
 			}
 			pi.Parameters = append(pi.Parameters, p)
 		}
@@ -239,28 +323,22 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 			op.Description = props.Description
 			op.Deprecated = props.Deprecated
 
+			// By default every operation requires a Vault token. Unauthenticated
+			// paths explicitly declare an empty security requirement, and a
+			// backend may override this entirely (e.g. AWS auth login accepts a
+			// signed request instead of a token).
+			switch {
+			case props.Security != nil:
+				op.Security = props.Security
+			case pi.Unauthenticated:
+				op.Security = []map[string][]string{}
+			default:
+				op.Security = oasDefaultSecurity
+			}
+
 			// Add any fields not present in the path as body parameters for POST.
 			if opType == logical.CreateOperation || opType == logical.UpdateOperation {
-				s := &oasSchema{
-					Type:       "object",
-					Properties: make(map[string]*oasSchema),
-				}
-
-				for name, field := range bodyFields {
-					openapiField := convertType(field.Type)
-					p := oasSchema{
-						Type:        openapiField.baseType,
-						Description: cleanString(field.Description),
-						Format:      openapiField.format,
-						Deprecated:  field.Deprecated,
-					}
-					if openapiField.baseType == "array" {
-						p.Items = &oasSchema{
-							Type: openapiField.items,
-						}
-					}
-					s.Properties[name] = &p
-				}
+				s := fieldsToSchema(bodyFields)
 
 				// If examples were given, use the first one as the sample
 				// of this schema.
@@ -270,10 +348,15 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 
 				// Set the final request body. Only JSON request data is supported.
 				if len(s.Properties) > 0 || s.Example != nil {
+					bodySchema := s
+					if len(s.Properties) > 0 {
+						bodySchema = registerSchema(doc, schemaName(backendType, path, "Request"), s)
+					}
+
 					op.RequestBody = &oasRequestBody{
 						Content: oasContent{
 							"application/json": &oasMediaTypeObject{
-								Schema: s,
+								Schema: bodySchema,
 							},
 						},
 					}
@@ -313,33 +396,66 @@ func documentPath(p *Path, specialPaths *logical.Paths, backendType logical.Back
 			// Add any defined response details.
 			for code, responses := range props.Responses {
 				var description string
-				content := make(oasContent)
+
+				// Multiple media types are supported per response (e.g.
+				// "application/json" alongside "application/pkix-cert" for
+				// an endpoint that can also return a raw certificate), so
+				// every entry in responses is merged by media type rather
+				// than only the first one seen for a given type. Merging
+				// happens in full before anything is registered as a $ref,
+				// since a registered schema only carries a $ref from then
+				// on and can no longer be mutated in place.
+				byMediaType := make(map[string]*oasSchema)
+				var mediaTypes []string
 
 				for i, resp := range responses {
 					if i == 0 {
 						description = resp.Description
 					}
-					if resp.Example != nil {
-						mediaType := resp.MediaType
-						if mediaType == "" {
-							mediaType = "application/json"
-						}
 
+					if resp.Example == nil && len(resp.Fields) == 0 {
+						continue
+					}
+
+					mediaType := resp.MediaType
+					if mediaType == "" {
+						mediaType = "application/json"
+					}
+
+					schema, ok := byMediaType[mediaType]
+					if !ok {
+						schema = &oasSchema{}
+						byMediaType[mediaType] = schema
+						mediaTypes = append(mediaTypes, mediaType)
+					}
+
+					// A response may declare a typed schema (Fields), an
+					// example payload, or both; merge whichever are present
+					// rather than letting the example take priority.
+					if len(resp.Fields) > 0 {
+						fieldSchema := fieldsToSchema(resp.Fields)
+						schema.Type = fieldSchema.Type
+						schema.Properties = fieldSchema.Properties
+						schema.Required = fieldSchema.Required
+					}
+
+					if resp.Example != nil {
 						// create a version of the response that will not emit null items
 						cr, err := cleanResponse(resp.Example)
 						if err != nil {
 							return err
 						}
+						schema.Example = cr
+					}
+				}
 
-						// Only one example per media type is allowed, so first one wins
-						if _, ok := content[mediaType]; !ok {
-							content[mediaType] = &oasMediaTypeObject{
-								Schema: &oasSchema{
-									Example: cr,
-								},
-							}
-						}
+				content := make(oasContent)
+				for _, mediaType := range mediaTypes {
+					schema := byMediaType[mediaType]
+					if len(schema.Properties) > 0 {
+						schema = registerSchema(doc, schemaName(backendType, path, "Response"+code), schema)
 					}
+					content[mediaType] = &oasMediaTypeObject{Schema: schema}
 				}
 
 				op.Responses[code] = &oasResponse{
@@ -375,9 +491,16 @@ func specialPathMatch(path string, specialPaths []string) bool {
 	return false
 }
 
-// expandPattern expands a regex pattern by generating permutations of any optional parameters
-// and changing named parameters into their {openapi} equivalents.
-func expandPattern(pattern string) []string {
+// namedParamRe captures a named parameter along with its inner regex body,
+// e.g. "(?P<path>.+)" -> name "path", body ".+". Unlike reqdRe, the body is
+// kept so callers can tell whether it spans multiple path segments.
+var namedParamRe = regexp.MustCompile(`\(\?P<(\w+)>([^)]*)\)`)
+
+// expandPattern expands a regex pattern by generating permutations of any
+// optional parameters and alternations, materializing multi-segment named
+// parameters into concrete sub-paths when examples are available, and
+// changing any remaining named parameters into their {openapi} equivalents.
+func expandPattern(pattern string, fields map[string]*FieldSchema) ([]string, map[string]map[string]bool) {
 	var paths []string
 
 	// GenericNameRegex adds a regex that complicates our parsing. It is much easier to
@@ -394,14 +517,9 @@ func expandPattern(pattern string) []string {
 
 	pattern = strings.Replace(pattern, regexToRemove, "", -1)
 
-	// Initialize paths with the original pattern or the halves of an
-	// alternation, which is also present in some patterns.
-	matches := altRe.FindAllStringSubmatch(pattern, -1)
-	if len(matches) > 0 {
-		paths = []string{matches[0][1], matches[0][2]}
-	} else {
-		paths = []string{pattern}
-	}
+	// Expand every top-level alternation group, including ones nested inside
+	// another (e.g. "(raw/?$|raw/(?P<path>.+))"), not just the first match.
+	paths = expandAlternations(pattern)
 
 	// Expand all optional regex elements into two paths. This approach is really only useful up to 2 optional
 	// groups, but we probably don't want to deal with the exponential increase beyond that anyway.
@@ -423,10 +541,32 @@ func expandPattern(pattern string) []string {
 		}
 	}
 
-	// Replace named parameters (?P<foo>) with {foo}
+	// Materialize named parameters whose regex spans multiple path segments
+	// (e.g. "(?P<path>.+)") into concrete literal paths when the field
+	// supplies a list of expected sub-patterns.
+	var materialized []string
+	for _, path := range paths {
+		materialized = append(materialized, expandMultiSegmentParams(path, fields)...)
+	}
+	paths = materialized
+
+	// Replace any remaining named parameters (?P<foo>) with {foo}, noting
+	// which of them still span multiple path segments so the caller can
+	// annotate them as such. This is tracked per expanded path rather than
+	// globally: an alternation can reuse the same field name as an ordinary
+	// single-segment parameter on one branch and a multi-segment one on
+	// another, and the two must not bleed into each other.
+	multiSegment := make(map[string]map[string]bool)
 	var replacedPaths []string
 
 	for _, path := range paths {
+		pathMultiSegment := make(map[string]bool)
+		for _, m := range namedParamRe.FindAllStringSubmatch(path, -1) {
+			if isMultiSegmentPattern(m[2]) {
+				pathMultiSegment[m[1]] = true
+			}
+		}
+
 		result := reqdRe.FindAllStringSubmatch(path, -1)
 		if result != nil {
 			for _, p := range result {
@@ -438,9 +578,127 @@ func expandPattern(pattern string) []string {
 		path = cleanSuffixRe.ReplaceAllString(path, "")
 		path = cleanCharsRe.ReplaceAllString(path, "")
 		replacedPaths = append(replacedPaths, path)
+		multiSegment[path] = pathMultiSegment
+	}
+
+	return replacedPaths, multiSegment
+}
+
+// expandAlternations repeatedly splits pattern on an alternation (see
+// splitAlternation) -- a "|" outside any group, or else the left-most
+// parenthesized group containing a "|" at that group's own nesting depth --
+// until no alternations remain. Unlike a single
+// regexp match, this correctly handles multiple alternation groups in the
+// same pattern as well as alternations nested inside another group.
+func expandAlternations(pattern string) []string {
+	queue := []string{pattern}
+	var out []string
+
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+
+		prefix, left, right, suffix, ok := splitAlternation(p)
+		if !ok {
+			out = append(out, p)
+			continue
+		}
+
+		queue = append(queue, prefix+left+suffix, prefix+right+suffix)
+	}
+
+	return out
+}
+
+// splitAlternation splits pattern on a "|" outside any group into its two
+// alternatives, with an empty prefix and suffix. Failing that, it finds the
+// left-most parenthesized group in pattern that contains a "|" at that
+// group's own nesting depth, and splits it into the text before the group,
+// its two alternatives, and the text after it.
+func splitAlternation(pattern string) (prefix, left, right, suffix string, ok bool) {
+	topDepth := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '(':
+			topDepth++
+		case ')':
+			topDepth--
+		case '|':
+			if topDepth == 0 {
+				return "", pattern[:i], pattern[i+1:], "", true
+			}
+		}
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '(' {
+			continue
+		}
+
+		depth := 0
+		pipe := -1
+		end := -1
+
+		for j := i; j < len(pattern); j++ {
+			switch pattern[j] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					end = j
+				}
+			case '|':
+				if depth == 1 && pipe == -1 {
+					pipe = j
+				}
+			}
+			if end != -1 {
+				break
+			}
+		}
+
+		if end == -1 || pipe == -1 {
+			continue
+		}
+
+		return pattern[:i], pattern[i+1 : pipe], pattern[pipe+1 : end], pattern[end+1:], true
 	}
 
-	return replacedPaths
+	return "", "", "", "", false
+}
+
+// isMultiSegmentPattern reports whether a named parameter's inner regex can
+// match more than one path segment, i.e. it contains a literal slash or an
+// unbounded wildcard.
+func isMultiSegmentPattern(body string) bool {
+	return strings.Contains(body, "/") || strings.Contains(body, ".+") || strings.Contains(body, ".*")
+}
+
+// expandMultiSegmentParams materializes a path's multi-segment named
+// parameters (see isMultiSegmentPattern) into one concrete path per entry in
+// the corresponding field's PathExamples, replacing the wildcard with a
+// literal segment that may itself contain nested {param} placeholders. A
+// path is returned unchanged when it has no multi-segment parameter, or when
+// the field declares no PathExamples -- in which case the wildcard survives
+// as a single {param} annotated with x-vault-multi-segment instead.
+func expandMultiSegmentParams(path string, fields map[string]*FieldSchema) []string {
+	m := namedParamRe.FindStringSubmatch(path)
+	if m == nil || !isMultiSegmentPattern(m[2]) {
+		return []string{path}
+	}
+
+	field := fields[m[1]]
+	if field == nil || len(field.PathExamples) == 0 {
+		return []string{path}
+	}
+
+	var out []string
+	for _, example := range field.PathExamples {
+		out = append(out, expandMultiSegmentParams(strings.Replace(path, m[0], example, 1), fields)...)
+	}
+
+	return out
 }
 
 // schemaType is a subset of the JSON Schema elements used as a target
@@ -492,6 +750,108 @@ func convertType(t FieldType) schemaType {
 	return ret
 }
 
+// fieldsToSchema builds an object schema whose properties are derived from a
+// set of framework.FieldSchema, the same conversion used for request bodies.
+// It is also used to describe typed response bodies.
+func fieldsToSchema(fields map[string]*FieldSchema) *oasSchema {
+	s := &oasSchema{
+		Type:       "object",
+		Properties: make(map[string]*oasSchema),
+	}
+
+	for name, field := range fields {
+		openapiField := convertType(field.Type)
+		format := openapiField.format
+		if field.Format != "" {
+			format = field.Format
+		}
+
+		p := oasSchema{
+			Type:        openapiField.baseType,
+			Description: cleanString(field.Description),
+			Format:      format,
+			Pattern:     field.Pattern,
+			Deprecated:  field.Deprecated,
+			ReadOnly:    field.ReadOnly,
+			WriteOnly:   field.WriteOnly,
+		}
+		if openapiField.baseType == "array" {
+			p.Items = &oasSchema{
+				Type: openapiField.items,
+			}
+		}
+		s.Properties[name] = &p
+
+		if field.Required {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	sort.Strings(s.Required)
+
+	return s
+}
+
+// schemaNameWordRe splits a path pattern into the segments used to build a
+// components.schemas key, e.g. "config/lease" -> ["config", "lease"].
+var schemaNameWordRe = regexp.MustCompile(`[A-Za-z0-9]+`)
+
+// schemaName derives a deterministic, human-readable components.schemas key
+// for a path's request or response schema, e.g. "SecretConfigLeaseRequest".
+func schemaName(backendType logical.BackendType, pattern string, suffix string) string {
+	var name strings.Builder
+
+	switch backendType {
+	case logical.TypeLogical:
+		name.WriteString("Secret")
+	case logical.TypeCredential:
+		name.WriteString("Auth")
+	default:
+		name.WriteString("System")
+	}
+
+	for _, word := range schemaNameWordRe.FindAllString(pattern, -1) {
+		name.WriteString(strings.Title(strings.ToLower(word)))
+	}
+
+	name.WriteString(suffix)
+
+	return name.String()
+}
+
+// registerSchema stores schema under name in doc.Components.Schemas and
+// returns a schema that $ref's it. Structurally-identical schemas already
+// present in the document are reused instead of minting a duplicate entry,
+// and name collisions between distinct schemas are disambiguated with a
+// numeric suffix.
+func registerSchema(doc *OASDocument, name string, schema *oasSchema) *oasSchema {
+	if doc.Components.Schemas == nil {
+		doc.Components.Schemas = make(map[string]*oasSchema)
+	}
+
+	encoded, err := json.Marshal(schema)
+	if err == nil {
+		for existingName, existing := range doc.Components.Schemas {
+			existingEncoded, err := json.Marshal(existing)
+			if err == nil && bytes.Equal(encoded, existingEncoded) {
+				return &oasSchema{Ref: "#/components/schemas/" + existingName}
+			}
+		}
+	}
+
+	finalName := name
+	for i := 2; ; i++ {
+		if _, taken := doc.Components.Schemas[finalName]; !taken {
+			break
+		}
+		finalName = fmt.Sprintf("%s%d", name, i)
+	}
+
+	doc.Components.Schemas[finalName] = schema
+
+	return &oasSchema{Ref: "#/components/schemas/" + finalName}
+}
+
 // cleanString prepares s for inclusion in the output
 func cleanString(s string) string {
 	// clean leading/trailing whitespace, and replace whitespace runs into a single space
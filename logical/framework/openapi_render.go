@@ -0,0 +1,374 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/logical"
+	"sigs.k8s.io/yaml"
+)
+
+// OpenAPI output formats supported by RenderOpenAPI. A caller exposing this
+// over HTTP (e.g. sys/internal/specs/openapi) is expected to select one of
+// these via a "format" query parameter, defaulting to FormatJSON.
+const (
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatOAS31    = "oas31"
+	FormatSwagger2 = "swagger2"
+)
+
+// RenderOpenAPI serializes doc as format, which must be one of FormatJSON,
+// FormatYAML, FormatOAS31 or FormatSwagger2 (the empty string is treated as
+// FormatJSON). OAS 3.1 and Swagger 2.0 are produced by reshaping the
+// in-memory 3.0.2 document rather than generating it directly; the Swagger
+// 2.0 conversion is lossy, collapsing a JSON request body into a single
+// "in: body" parameter and moving components.schemas to definitions.
+func (doc *OASDocument) RenderOpenAPI(format string) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case FormatYAML:
+		return yaml.Marshal(doc)
+	case FormatOAS31:
+		generic, err := doc.toOAS31()
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(generic, "", "  ")
+	case FormatSwagger2:
+		generic, err := doc.toSwagger2()
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(generic, "", "  ")
+	default:
+		return nil, fmt.Errorf("unsupported OpenAPI format %q", format)
+	}
+}
+
+// RenderOpenAPI generates this backend's OpenAPI document and serializes it
+// per format; see OASDocument.RenderOpenAPI.
+func (b *Backend) RenderOpenAPI(format string) ([]byte, error) {
+	doc := NewOASDocument()
+	if err := documentPaths(b, doc); err != nil {
+		return nil, err
+	}
+
+	return doc.RenderOpenAPI(format)
+}
+
+// openAPIContentType maps a RenderOpenAPI format to the content-type the
+// rendered bytes should be served with.
+func openAPIContentType(format string) string {
+	if format == FormatYAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// OpenAPISpecHandler returns an OperationHandler that serves this backend's
+// rendered OpenAPI document, reading the requested format from the "format"
+// request field (one of FormatYAML, FormatOAS31, FormatSwagger2, defaulting
+// to FormatJSON). A backend mounts it in its Paths, typically at
+// "internal/specs/openapi", so that GET
+// sys/internal/specs/openapi?format=yaml|oas31|swagger2 reaches
+// RenderOpenAPI over HTTP instead of only being callable from Go.
+func (b *Backend) OpenAPISpecHandler() OperationHandler {
+	return &PathOperation{
+		Summary: "Generate an OpenAPI document for this backend's mounted paths.",
+		Callback: func(_ context.Context, _ *logical.Request, data *FieldData) (*logical.Response, error) {
+			format, _ := data.Raw["format"].(string)
+
+			body, err := b.RenderOpenAPI(format)
+			if err != nil {
+				return nil, err
+			}
+
+			return &logical.Response{
+				Data: map[string]interface{}{
+					"http_content_type": openAPIContentType(format),
+					"http_raw_body":     body,
+					"http_status_code":  200,
+				},
+			}, nil
+		},
+	}
+}
+
+// toGenericDoc round-trips doc through JSON into a plain map so format
+// conversions can reshape it without a parallel set of typed 3.1/Swagger 2.0
+// structs.
+func toGenericDoc(doc *OASDocument) (map[string]interface{}, error) {
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic map[string]interface{}
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+
+	return generic, nil
+}
+
+// toOAS31 reshapes doc into an OpenAPI 3.1 document: the version is bumped
+// to 3.1.0, and every schema's `example`/`nullable` is translated to 3.1's
+// JSON Schema 2020-12 equivalents (`examples`/a union-typed `type`).
+func (doc *OASDocument) toOAS31() (map[string]interface{}, error) {
+	generic, err := toGenericDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	generic["openapi"] = "3.1.0"
+	walkGenericMaps(generic, oas31ifySchema)
+
+	return generic, nil
+}
+
+// oas31ifySchema rewrites a single schema object in place to 3.1 semantics.
+// It is a no-op on maps that aren't schemas, since they simply lack the keys
+// being checked for.
+func oas31ifySchema(m map[string]interface{}) {
+	if example, ok := m["example"]; ok {
+		delete(m, "example")
+		m["examples"] = []interface{}{example}
+	}
+
+	if nullable, ok := m["nullable"].(bool); ok {
+		delete(m, "nullable")
+		if nullable {
+			if t, ok := m["type"].(string); ok {
+				m["type"] = []interface{}{t, "null"}
+			}
+		}
+	}
+
+	if min, ok := m["exclusiveMinimum"].(bool); ok {
+		delete(m, "exclusiveMinimum")
+		if min {
+			if v, ok := m["minimum"]; ok {
+				m["exclusiveMinimum"] = v
+				delete(m, "minimum")
+			}
+		}
+	}
+}
+
+// walkGenericMaps calls fn on every map[string]interface{} reachable from v,
+// including v itself.
+func walkGenericMaps(v interface{}, fn func(map[string]interface{})) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		fn(t)
+		for _, child := range t {
+			walkGenericMaps(child, fn)
+		}
+	case []interface{}:
+		for _, child := range t {
+			walkGenericMaps(child, fn)
+		}
+	}
+}
+
+// toSwagger2 down-converts doc into a (lossy) Swagger 2.0 document for
+// legacy tooling that doesn't understand OpenAPI 3.x.
+func (doc *OASDocument) toSwagger2() (map[string]interface{}, error) {
+	generic, err := toGenericDoc(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	swagger2 := map[string]interface{}{
+		"swagger": "2.0",
+		"info":    generic["info"],
+	}
+
+	if components, ok := generic["components"].(map[string]interface{}); ok {
+		if schemas, ok := components["schemas"].(map[string]interface{}); ok {
+			definitions := make(map[string]interface{}, len(schemas))
+			for name, schema := range schemas {
+				definitions[name] = rewriteSchemaRefs(schema)
+			}
+			swagger2["definitions"] = definitions
+		}
+
+		if securitySchemes, ok := components["securitySchemes"]; ok {
+			// apiKey security schemes are already shape-compatible between
+			// OAS 3.0 and Swagger 2.0 securityDefinitions.
+			swagger2["securityDefinitions"] = securitySchemes
+		}
+	}
+
+	if pathsRaw, ok := generic["paths"].(map[string]interface{}); ok {
+		paths := make(map[string]interface{}, len(pathsRaw))
+		for name, pathItem := range pathsRaw {
+			if pi, ok := pathItem.(map[string]interface{}); ok {
+				paths[name] = swagger2PathItem(pi)
+			}
+		}
+		swagger2["paths"] = paths
+	}
+
+	return swagger2, nil
+}
+
+// swagger2PathItem converts one OAS 3.0 path item to its Swagger 2.0
+// equivalent: its operations and its shared path-level parameters are
+// converted, while x-vault-* extensions and other fields are left as-is.
+func swagger2PathItem(pi map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(pi))
+
+	for key, value := range pi {
+		switch key {
+		case "get", "post", "delete":
+			if op, ok := value.(map[string]interface{}); ok {
+				out[key] = swagger2Operation(op)
+			}
+		case "parameters":
+			origParams, _ := value.([]interface{})
+			params := make([]interface{}, 0, len(origParams))
+			for _, p := range origParams {
+				if param, ok := p.(map[string]interface{}); ok {
+					params = append(params, swagger2Parameter(param))
+				} else {
+					params = append(params, p)
+				}
+			}
+			out[key] = params
+		default:
+			out[key] = value
+		}
+	}
+
+	return out
+}
+
+// swagger2Operation converts one OAS 3.0 operation to Swagger 2.0: the JSON
+// request body becomes a single "in: body" parameter, and each response's
+// content schema moves up to a top-level "schema" field.
+func swagger2Operation(op map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(op))
+
+	for key, value := range op {
+		if key != "requestBody" && key != "responses" {
+			out[key] = value
+		}
+	}
+
+	origParams, _ := op["parameters"].([]interface{})
+	params := make([]interface{}, 0, len(origParams))
+	for _, p := range origParams {
+		if param, ok := p.(map[string]interface{}); ok {
+			params = append(params, swagger2Parameter(param))
+		} else {
+			params = append(params, p)
+		}
+	}
+
+	if requestBody, ok := op["requestBody"].(map[string]interface{}); ok {
+		if schema := jsonMediaSchema(requestBody); schema != nil {
+			params = append(params, map[string]interface{}{
+				"name":     "body",
+				"in":       "body",
+				"required": true,
+				"schema":   rewriteSchemaRefs(schema),
+			})
+		}
+	}
+
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	if responses, ok := op["responses"].(map[string]interface{}); ok {
+		swaggerResponses := make(map[string]interface{}, len(responses))
+		for code, respRaw := range responses {
+			resp, ok := respRaw.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			swaggerResp := map[string]interface{}{"description": resp["description"]}
+			if schema := jsonMediaSchema(resp); schema != nil {
+				swaggerResp["schema"] = rewriteSchemaRefs(schema)
+			}
+			swaggerResponses[code] = swaggerResp
+		}
+		out["responses"] = swaggerResponses
+	}
+
+	return out
+}
+
+// swagger2Parameter converts one OAS 3.0 non-body parameter to Swagger 2.0:
+// unlike OAS 3.0, Swagger 2.0 requires a path/query/header parameter to
+// declare "type"/"format" directly on itself rather than nested under
+// "schema", which isn't a valid Swagger 2.0 parameter field at all.
+func swagger2Parameter(param map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(param))
+	for key, value := range param {
+		if key != "schema" {
+			out[key] = value
+		}
+	}
+
+	if schema, ok := param["schema"].(map[string]interface{}); ok {
+		if t, ok := schema["type"]; ok {
+			out["type"] = t
+		}
+		if format, ok := schema["format"]; ok {
+			out["format"] = format
+		}
+	}
+
+	return out
+}
+
+// jsonMediaSchema extracts the application/json media type's schema from an
+// OAS 3.0 requestBody or response object, or nil if there isn't one.
+func jsonMediaSchema(withContent map[string]interface{}) interface{} {
+	content, ok := withContent["content"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	media, ok := content["application/json"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	return media["schema"]
+}
+
+// rewriteSchemaRefs rewrites every "$ref" in v from the OAS 3.0
+// "#/components/schemas/Foo" form to the Swagger 2.0 "#/definitions/Foo"
+// form.
+func rewriteSchemaRefs(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, val := range t {
+			if k == "$ref" {
+				if ref, ok := val.(string); ok {
+					out[k] = strings.Replace(ref, "#/components/schemas/", "#/definitions/", 1)
+					continue
+				}
+			}
+			out[k] = rewriteSchemaRefs(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, val := range t {
+			out[i] = rewriteSchemaRefs(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,415 @@
+package framework
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+func TestSplitAlternation(t *testing.T) {
+	cases := []struct {
+		pattern string
+		left    string
+		right   string
+		ok      bool
+	}{
+		{"raw/?$|raw/(?P<path>.+)", "raw/?$", "raw/(?P<path>.+)", true},
+		{"(raw/?$|raw/(?P<path>.+))", "raw/?$", "raw/(?P<path>.+)", true},
+		{"(?P<path>.+)", "", "", false},
+		{"plain/path", "", "", false},
+	}
+
+	for _, c := range cases {
+		_, left, right, _, ok := splitAlternation(c.pattern)
+		if ok != c.ok {
+			t.Fatalf("splitAlternation(%q) ok = %v, want %v", c.pattern, ok, c.ok)
+		}
+		if ok && (left != c.left || right != c.right) {
+			t.Fatalf("splitAlternation(%q) = (%q, %q), want (%q, %q)", c.pattern, left, right, c.left, c.right)
+		}
+	}
+}
+
+func TestExpandAlternations_HandlesMultipleAndNestedGroups(t *testing.T) {
+	got := expandAlternations("foo/(a|b)/(c|d)")
+	want := []string{"foo/a/c", "foo/a/d", "foo/b/c", "foo/b/d"}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandAlternations with two alternation groups = %v, want %v", got, want)
+	}
+
+	got = expandAlternations("raw/?$|raw/(?P<path>.+)")
+	want = []string{"raw/?$", "raw/(?P<path>.+)"}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandAlternations with a nested group = %v, want %v", got, want)
+	}
+}
+
+func TestExpandMultiSegmentParams(t *testing.T) {
+	fields := map[string]*FieldSchema{
+		"path": {Type: TypeString, PathExamples: []string{"foo/bar", "role/{role_name}"}},
+	}
+
+	got := expandMultiSegmentParams("secret/(?P<path>.+)", fields)
+	want := []string{"secret/foo/bar", "secret/role/{role_name}"}
+	sort.Strings(got)
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandMultiSegmentParams = %v, want %v", got, want)
+	}
+
+	// No PathExamples: the wildcard survives unexpanded, to be flagged as
+	// multi-segment later in expandPattern and annotated with
+	// x-vault-multi-segment by documentPath.
+	got = expandMultiSegmentParams("secret/(?P<path>.+)", map[string]*FieldSchema{})
+	want = []string{"secret/(?P<path>.+)"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expandMultiSegmentParams with no PathExamples = %v, want %v", got, want)
+	}
+}
+
+// TestDocumentPath_NestedPlaceholderWithoutFieldSchema guards against a
+// nil-pointer panic when a PathExamples expansion introduces a nested
+// {param} placeholder that has no corresponding entry in the Path's Fields.
+func TestDocumentPath_NestedPlaceholderWithoutFieldSchema(t *testing.T) {
+	p := &Path{
+		Pattern:      `secret/(?P<path>.+)`,
+		HelpSynopsis: "Read a secret.",
+		Fields: map[string]*FieldSchema{
+			"path": {
+				Type:         TypeString,
+				Description:  "Path of the secret.",
+				PathExamples: []string{"role/{role_name}"},
+			},
+		},
+		Operations: map[logical.Operation]OperationHandler{
+			logical.ReadOperation: &PathOperation{Summary: "Read."},
+		},
+	}
+
+	doc := NewOASDocument()
+	if err := documentPath(p, nil, logical.TypeLogical, doc); err != nil {
+		t.Fatalf("documentPath returned error: %v", err)
+	}
+
+	if _, ok := doc.Paths["/secret/role/{role_name}"]; !ok {
+		t.Fatalf("expected /secret/role/{role_name} to be documented, got paths: %v", doc.Paths)
+	}
+}
+
+func TestRegisterSchema_DedupesStructurallyIdenticalSchemas(t *testing.T) {
+	doc := NewOASDocument()
+
+	a := &oasSchema{Type: "object", Properties: map[string]*oasSchema{"foo": {Type: "string"}}}
+	b := &oasSchema{Type: "object", Properties: map[string]*oasSchema{"foo": {Type: "string"}}}
+
+	refA := registerSchema(doc, "FooRequest", a)
+	refB := registerSchema(doc, "BarRequest", b)
+
+	if len(doc.Components.Schemas) != 1 {
+		t.Fatalf("expected structurally identical schemas to share one components entry, got %d", len(doc.Components.Schemas))
+	}
+	if refA.Ref != refB.Ref {
+		t.Fatalf("expected both refs to point at the same schema, got %q and %q", refA.Ref, refB.Ref)
+	}
+}
+
+func TestRegisterSchema_DisambiguatesNameCollisions(t *testing.T) {
+	doc := NewOASDocument()
+
+	a := &oasSchema{Type: "object", Properties: map[string]*oasSchema{"foo": {Type: "string"}}}
+	b := &oasSchema{Type: "object", Properties: map[string]*oasSchema{"bar": {Type: "string"}}}
+
+	refA := registerSchema(doc, "SameName", a)
+	refB := registerSchema(doc, "SameName", b)
+
+	if refA.Ref == refB.Ref {
+		t.Fatalf("expected distinct schemas with a name collision to get distinct refs, both got %q", refA.Ref)
+	}
+	if len(doc.Components.Schemas) != 2 {
+		t.Fatalf("expected 2 components entries, got %d", len(doc.Components.Schemas))
+	}
+}
+
+// writeOnlyBackend returns a Backend whose single path declares a writeOnly
+// "password" response field, for exercising EnableOpenAPIValidation +
+// HandleRequest end to end.
+func writeOnlyBackend(t *testing.T, responseData map[string]interface{}) *Backend {
+	t.Helper()
+
+	b := &Backend{
+		BackendType: logical.TypeLogical,
+		Paths: []*Path{
+			{
+				Pattern:      "creds/(?P<name>\\w+)",
+				HelpSynopsis: "Generate credentials.",
+				Fields: map[string]*FieldSchema{
+					"name": {Type: TypeString, Description: "Name of the role."},
+				},
+				Operations: map[logical.Operation]OperationHandler{
+					logical.ReadOperation: &PathOperation{
+						Summary: "Generate credentials.",
+						Responses: map[string][]Response{
+							"200": {
+								{
+									Description: "OK",
+									Fields: map[string]*FieldSchema{
+										"username": {Type: TypeString, Description: "username"},
+										"password": {Type: TypeString, Description: "password", WriteOnly: true},
+									},
+								},
+							},
+						},
+						Callback: func(_ context.Context, _ *logical.Request, _ *FieldData) (*logical.Response, error) {
+							return &logical.Response{Data: responseData}, nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if err := b.EnableOpenAPIValidation(ValidationOptions{ValidateResponses: true}); err != nil {
+		t.Fatalf("EnableOpenAPIValidation returned error: %v", err)
+	}
+
+	return b
+}
+
+func TestBackend_HandleRequest_RejectsWriteOnlyFieldInResponse(t *testing.T) {
+	b := writeOnlyBackend(t, map[string]interface{}{
+		"username": "alice",
+		"password": "hunter2",
+	})
+
+	req := &logical.Request{Operation: logical.ReadOperation, Path: "creds/deploy"}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected HandleRequest to reject a writeOnly field in the response, got nil error")
+	}
+	if !strings.Contains(err.Error(), "writeOnly") {
+		t.Fatalf("expected a writeOnly validation error, got: %v", err)
+	}
+}
+
+func TestBackend_HandleRequest_AllowsResponseWithoutWriteOnlyField(t *testing.T) {
+	b := writeOnlyBackend(t, map[string]interface{}{
+		"username": "alice",
+	})
+
+	req := &logical.Request{Operation: logical.ReadOperation, Path: "creds/deploy"}
+	resp, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatalf("HandleRequest returned unexpected error: %v", err)
+	}
+	if resp.Data["username"] != "alice" {
+		t.Fatalf("expected the response to pass through unchanged, got %+v", resp.Data)
+	}
+}
+
+func TestBackend_HandleRequest_RejectsRequestViolatingPattern(t *testing.T) {
+	b := &Backend{
+		BackendType: logical.TypeLogical,
+		Paths: []*Path{
+			{
+				Pattern:      "role/(?P<name>\\w+)",
+				HelpSynopsis: "Configure a role.",
+				Fields: map[string]*FieldSchema{
+					"name": {Type: TypeString, Description: "Name of the role."},
+				},
+				Operations: map[logical.Operation]OperationHandler{
+					logical.UpdateOperation: &PathOperation{
+						Summary: "Configure the role.",
+						Responses: map[string][]Response{
+							"200": {{Description: "OK"}},
+						},
+						Callback: func(_ context.Context, _ *logical.Request, _ *FieldData) (*logical.Response, error) {
+							t.Fatalf("handler should not be reached when request validation fails")
+							return nil, nil
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b.Paths[0].Fields["cidr"] = &FieldSchema{Type: TypeString, Pattern: `^\d+\.\d+\.\d+\.\d+/\d+$`}
+
+	if err := b.EnableOpenAPIValidation(ValidationOptions{}); err != nil {
+		t.Fatalf("EnableOpenAPIValidation returned error: %v", err)
+	}
+
+	req := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/deploy",
+		Data:      map[string]interface{}{"cidr": "not-a-cidr"},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err == nil {
+		t.Fatalf("expected HandleRequest to reject a request violating the declared pattern, got nil error")
+	}
+	if !strings.Contains(err.Error(), "pattern") {
+		t.Fatalf("expected a pattern validation error, got: %v", err)
+	}
+}
+
+func TestBackend_OpenAPISpecHandlerRendersRequestedFormat(t *testing.T) {
+	b := &Backend{
+		BackendType: logical.TypeLogical,
+		Paths: []*Path{
+			{
+				Pattern:      "config",
+				HelpSynopsis: "Configure the backend.",
+				Operations: map[logical.Operation]OperationHandler{
+					logical.ReadOperation: &PathOperation{Summary: "Read config."},
+				},
+			},
+		},
+	}
+
+	handler := b.OpenAPISpecHandler()
+
+	for _, format := range []string{"", FormatYAML, FormatOAS31, FormatSwagger2} {
+		req := &logical.Request{Operation: logical.ReadOperation}
+		resp, err := handler.Handle(context.Background(), req, &FieldData{Raw: map[string]interface{}{"format": format}})
+		if err != nil {
+			t.Fatalf("OpenAPISpecHandler(%q) returned error: %v", format, err)
+		}
+
+		body, _ := resp.Data["http_raw_body"].([]byte)
+		if len(body) == 0 {
+			t.Fatalf("OpenAPISpecHandler(%q) returned an empty body", format)
+		}
+
+		wantContentType := "application/json"
+		if format == FormatYAML {
+			wantContentType = "application/yaml"
+		}
+		if got := resp.Data["http_content_type"]; got != wantContentType {
+			t.Fatalf("OpenAPISpecHandler(%q) content type = %v, want %v", format, got, wantContentType)
+		}
+	}
+}
+
+// TestToSwagger2_FlattensParameterSchema guards against regenerating invalid
+// Swagger 2.0: a non-body parameter must declare "type" on itself, not under
+// a nested "schema", which OAS 3.0 uses but Swagger 2.0 does not allow.
+func TestToSwagger2_FlattensParameterSchema(t *testing.T) {
+	p := &Path{
+		Pattern:      `config/(?P<name>\w+)`,
+		HelpSynopsis: "Configure a named resource.",
+		Fields: map[string]*FieldSchema{
+			"name": {Type: TypeString, Description: "Name of the resource."},
+		},
+		Operations: map[logical.Operation]OperationHandler{
+			logical.ReadOperation: &PathOperation{Summary: "Read the config."},
+		},
+	}
+
+	doc := NewOASDocument()
+	if err := documentPath(p, nil, logical.TypeLogical, doc); err != nil {
+		t.Fatalf("documentPath returned error: %v", err)
+	}
+
+	generic, err := doc.toSwagger2()
+	if err != nil {
+		t.Fatalf("toSwagger2 returned error: %v", err)
+	}
+
+	paths, ok := generic["paths"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected paths in swagger2 doc, got %+v", generic)
+	}
+
+	pathItem, ok := paths["/config/{name}"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected /config/{name} in swagger2 paths, got %+v", paths)
+	}
+
+	if _, ok := pathItem["get"].(map[string]interface{}); !ok {
+		t.Fatalf("expected a get operation, got %+v", pathItem)
+	}
+
+	// Path parameters are shared by every operation on the path, so
+	// documentPath puts them on the path item rather than the get operation.
+	params, ok := pathItem["parameters"].([]interface{})
+	if !ok || len(params) == 0 {
+		t.Fatalf("expected at least one parameter, got %+v", pathItem["parameters"])
+	}
+
+	param, ok := params[0].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected parameter to be a map, got %T", params[0])
+	}
+
+	if _, hasSchema := param["schema"]; hasSchema {
+		t.Fatalf("swagger2 non-body parameter must not carry a nested schema, got %+v", param)
+	}
+	if param["type"] != "string" {
+		t.Fatalf("swagger2 non-body parameter must declare type directly, got %+v", param)
+	}
+}
+
+func TestDocumentPath_MergesResponseFieldsAndExampleBeforeRegistering(t *testing.T) {
+	p := &Path{
+		Pattern:      "config/lease",
+		HelpSynopsis: "Configure the lease.",
+		Operations: map[logical.Operation]OperationHandler{
+			logical.ReadOperation: &PathOperation{
+				Summary: "Read the lease config.",
+				Responses: map[string][]Response{
+					"200": {
+						{
+							Description: "OK",
+							Fields: map[string]*FieldSchema{
+								"ttl": {Type: TypeDurationSecond, Description: "lease ttl"},
+							},
+						},
+						{
+							Description: "OK",
+							Example: &logical.Response{
+								Data: map[string]interface{}{"ttl": 300},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	doc := NewOASDocument()
+	if err := documentPath(p, nil, logical.TypeLogical, doc); err != nil {
+		t.Fatalf("documentPath returned error: %v", err)
+	}
+
+	pi, ok := doc.Paths["/config/lease"]
+	if !ok {
+		t.Fatalf("expected /config/lease to be documented, got paths: %v", doc.Paths)
+	}
+
+	media := pi.Get.Responses["200"].Content["application/json"]
+	if media == nil || media.Schema == nil {
+		t.Fatalf("expected a response schema for application/json")
+	}
+	if media.Schema.Ref == "" {
+		t.Fatalf("expected the response schema to be registered as a $ref")
+	}
+
+	registered := doc.Components.Schemas[schemaName(logical.TypeLogical, "config/lease", "Response200")]
+	if registered == nil {
+		t.Fatalf("expected the merged schema to be registered in components.schemas")
+	}
+	if len(registered.Properties) != 1 || registered.Properties["ttl"] == nil {
+		t.Fatalf("expected the registered schema to carry the Fields-derived properties, got %+v", registered.Properties)
+	}
+	if registered.Example == nil {
+		t.Fatalf("expected the registered schema to also carry the example from the second response entry")
+	}
+}
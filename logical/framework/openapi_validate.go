@@ -0,0 +1,270 @@
+package framework
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// ValidationErrors aggregates every schema violation found while validating
+// a single request or response, rather than surfacing only the first one.
+type ValidationErrors []error
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, err := range v {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ValidationOptions controls what a RequestValidator enforces.
+type ValidationOptions struct {
+	// ValidateResponses additionally checks handler responses against the
+	// declared response schemas, including readOnly/writeOnly enforcement.
+	ValidateResponses bool
+}
+
+// RequestValidator checks request and response payloads against the
+// operations and schemas recorded in a generated OASDocument.
+type RequestValidator struct {
+	doc  *OASDocument
+	opts ValidationOptions
+}
+
+// NewRequestValidator builds a RequestValidator from a generated OASDocument.
+func NewRequestValidator(doc *OASDocument, opts ValidationOptions) *RequestValidator {
+	return &RequestValidator{doc: doc, opts: opts}
+}
+
+// ValidateRequest checks data (typically logical.Request.Data) against the
+// request body schema declared for oasPath and method. It returns a
+// ValidationErrors aggregating every violation found, or nil if the request
+// is valid or the operation declares no request body schema.
+func (rv *RequestValidator) ValidateRequest(oasPath, method string, data map[string]interface{}) error {
+	op := rv.operation(oasPath, method)
+	if op == nil || op.RequestBody == nil {
+		return nil
+	}
+
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	schema := rv.resolve(media.Schema)
+	if schema == nil {
+		return nil
+	}
+
+	if errs := validateAgainstSchema(schema, data, false); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateResponse checks data (typically logical.Response.Data) against the
+// response schema declared for code on oasPath/method, when response
+// validation is enabled.
+func (rv *RequestValidator) ValidateResponse(oasPath, method, code string, data map[string]interface{}) error {
+	if !rv.opts.ValidateResponses {
+		return nil
+	}
+
+	op := rv.operation(oasPath, method)
+	if op == nil {
+		return nil
+	}
+
+	resp, ok := op.Responses[code]
+	if !ok {
+		return nil
+	}
+
+	media, ok := resp.Content["application/json"]
+	if !ok {
+		return nil
+	}
+
+	schema := rv.resolve(media.Schema)
+	if schema == nil {
+		return nil
+	}
+
+	if errs := validateAgainstSchema(schema, data, true); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (rv *RequestValidator) operation(oasPath, method string) *OASOperation {
+	pi, ok := rv.doc.Paths[oasPath]
+	if !ok {
+		return nil
+	}
+
+	switch strings.ToUpper(method) {
+	case "GET":
+		return pi.Get
+	case "POST", "PUT":
+		return pi.Post
+	case "DELETE":
+		return pi.Delete
+	default:
+		return nil
+	}
+}
+
+// resolve follows a schema's $ref, if any, into doc.Components.Schemas.
+func (rv *RequestValidator) resolve(schema *oasSchema) *oasSchema {
+	if schema == nil || schema.Ref == "" {
+		return schema
+	}
+
+	name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+	return rv.doc.Components.Schemas[name]
+}
+
+// Middleware returns an http.Handler that validates each request's JSON body
+// against rv before invoking next, responding 400 with the aggregated
+// violations on failure. The request body is restored after validation so
+// next still sees it. r.URL.Path is looked up verbatim among the document's
+// paths, so a request whose URL has no exact entry there (for instance one
+// that fills in a {param} placeholder) passes through unvalidated.
+func (rv *RequestValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data map[string]interface{}
+
+		if r.Body != nil {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("unable to read request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+			if len(body) > 0 {
+				if err := json.Unmarshal(body, &data); err != nil {
+					http.Error(w, fmt.Sprintf("invalid JSON body: %v", err), http.StatusBadRequest)
+					return
+				}
+			}
+		}
+
+		if err := rv.ValidateRequest(r.URL.Path, r.Method, data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// EnableOpenAPIValidation generates this backend's OpenAPI document and turns
+// on request validation: once enabled, requests are checked against the
+// document and rejected with an aggregated error before reaching any
+// operation handler.
+func (b *Backend) EnableOpenAPIValidation(opts ValidationOptions) error {
+	doc := NewOASDocument()
+	if err := documentPaths(b, doc); err != nil {
+		return err
+	}
+
+	b.validator = NewRequestValidator(doc, opts)
+	return nil
+}
+
+// validateAgainstSchema checks data against schema's declared properties,
+// enforcing required fields, types, string formats/patterns, and
+// readOnly/writeOnly placement (forResponse selects which direction is
+// being checked). Every violation is collected; validation does not stop at
+// the first one.
+func validateAgainstSchema(schema *oasSchema, data map[string]interface{}, forResponse bool) ValidationErrors {
+	var errs ValidationErrors
+
+	for _, name := range schema.Required {
+		if _, ok := data[name]; !ok {
+			errs = append(errs, fmt.Errorf("missing required property %q", name))
+		}
+	}
+
+	for name, value := range data {
+		prop, ok := schema.Properties[name]
+		if !ok {
+			continue
+		}
+
+		if forResponse && prop.WriteOnly {
+			errs = append(errs, fmt.Errorf("property %q is writeOnly and must not appear in a response", name))
+			continue
+		}
+		if !forResponse && prop.ReadOnly {
+			errs = append(errs, fmt.Errorf("property %q is readOnly and must not be set in a request", name))
+			continue
+		}
+
+		if err := validatePropertyType(name, prop, value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+func validatePropertyType(name string, prop *oasSchema, value interface{}) error {
+	switch prop.Type {
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("property %q must be a string", name)
+		}
+		return validateStringConstraints(name, prop, s)
+	case "number":
+		switch value.(type) {
+		case float64, int, int64, json.Number:
+		default:
+			return fmt.Errorf("property %q must be a number", name)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("property %q must be a boolean", name)
+		}
+	case "array":
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("property %q must be an array", name)
+		}
+	}
+	return nil
+}
+
+func validateStringConstraints(name string, prop *oasSchema, value string) error {
+	switch prop.Format {
+	case "ipv4":
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() == nil {
+			return fmt.Errorf("property %q must be a valid IPv4 address", name)
+		}
+	case "ipv6":
+		ip := net.ParseIP(value)
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("property %q must be a valid IPv6 address", name)
+		}
+	}
+
+	if prop.Pattern != "" {
+		re, err := regexp.Compile(prop.Pattern)
+		if err != nil {
+			return fmt.Errorf("property %q has an invalid pattern %q: %w", name, prop.Pattern, err)
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("property %q does not match pattern %q", name, prop.Pattern)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,301 @@
+package framework
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/vault/logical"
+)
+
+// GenericNameRegex returns the standard regex used to capture a generic,
+// Vault-safe resource name as the named parameter name.
+func GenericNameRegex(name string) string {
+	return fmt.Sprintf(`(?P<%s>\w(([\w-.]+)?\w)?)`, name)
+}
+
+// FieldType is the type of a single field on a Path.
+type FieldType uint
+
+const (
+	TypeString FieldType = iota
+	TypeNameString
+	TypeLowerCaseString
+	TypeInt
+	TypeBool
+	TypeMap
+	TypeDurationSecond
+	TypeSlice
+	TypeStringSlice
+	TypeCommaStringSlice
+	TypeKVPairs
+	TypeCommaIntSlice
+	TypeHeader
+)
+
+// FieldSchema describes a single field accepted or returned by a Path,
+// either as a path/header parameter or a body property.
+type FieldSchema struct {
+	Type        FieldType
+	Description string
+	Required    bool
+	Deprecated  bool
+
+	// PathExamples lists concrete sub-paths this field may be bound to when
+	// its regex can span multiple path segments (e.g. "(?P<path>.+)").
+	// Each entry materializes into its own OpenAPI path; entries may
+	// themselves contain nested {param} placeholders.
+	PathExamples []string
+
+	// Pattern, when set, is a regular expression the field's string value
+	// must match, surfaced in the OpenAPI schema and enforced by
+	// RequestValidator.
+	Pattern string
+
+	// Format overrides the OpenAPI "format" otherwise derived from Type,
+	// e.g. "ipv4" or "ipv6".
+	Format string
+
+	// ReadOnly/WriteOnly mark a field as response-only or request-only
+	// respectively; RequestValidator rejects a WriteOnly field found in a
+	// response, and a ReadOnly field found in a request.
+	ReadOnly  bool
+	WriteOnly bool
+}
+
+// RequestExample is a sample request body used to populate an operation's
+// request schema example.
+type RequestExample struct {
+	Description string
+	Data        map[string]interface{}
+}
+
+// Response describes one possible response an operation may return for a
+// given status code.
+type Response struct {
+	Description string
+	MediaType   string
+	Example     *logical.Response
+	Fields      map[string]*FieldSchema
+}
+
+// OperationProperties carries the documentation-relevant data for a single
+// operation on a Path, independent of how that operation is implemented.
+type OperationProperties struct {
+	Summary     string
+	Description string
+	Examples    []RequestExample
+	Responses   map[string][]Response
+	Unpublished bool
+	Deprecated  bool
+
+	// Security overrides the default "requires a Vault token" security
+	// requirement for this operation, e.g. for AWS auth login endpoints
+	// that accept a signed request instead. A non-nil, empty slice means
+	// the operation is unauthenticated.
+	Security []map[string][]string
+}
+
+// OperationFunc implements the logic for a single operation on a Path.
+type OperationFunc func(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error)
+
+// OperationHandler is implemented by anything that can handle one operation
+// on a Path and describe itself for documentation purposes.
+type OperationHandler interface {
+	Handle(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error)
+	Properties() OperationProperties
+}
+
+// PathOperation is the standard OperationHandler implementation used by
+// Path.Operations.
+type PathOperation struct {
+	Callback    OperationFunc
+	Summary     string
+	Description string
+	Examples    []RequestExample
+	Responses   map[string][]Response
+	Unpublished bool
+	Deprecated  bool
+	Security    []map[string][]string
+}
+
+func (p *PathOperation) Handle(ctx context.Context, req *logical.Request, data *FieldData) (*logical.Response, error) {
+	return p.Callback(ctx, req, data)
+}
+
+func (p *PathOperation) Properties() OperationProperties {
+	return OperationProperties{
+		Summary:     p.Summary,
+		Description: p.Description,
+		Examples:    p.Examples,
+		Responses:   p.Responses,
+		Unpublished: p.Unpublished,
+		Deprecated:  p.Deprecated,
+		Security:    p.Security,
+	}
+}
+
+// FieldData is the bound set of raw request values for a Path's Fields,
+// handed to an OperationHandler.
+type FieldData struct {
+	Raw    map[string]interface{}
+	Schema map[string]*FieldSchema
+}
+
+// Path represents a single logical path (and its sub-paths/parameters)
+// served by a Backend.
+type Path struct {
+	Pattern         string
+	Fields          map[string]*FieldSchema
+	Operations      map[logical.Operation]OperationHandler
+	Callbacks       map[logical.Operation]OperationFunc
+	HelpSynopsis    string
+	HelpDescription string
+}
+
+// Backend is a framework-based implementation of logical.Backend.
+type Backend struct {
+	Paths           []*Path
+	BackendType     logical.BackendType
+	Unauthenticated []string
+
+	// validator, when set via EnableOpenAPIValidation, checks every request
+	// routed through HandleRequest against this backend's generated OpenAPI
+	// document before it reaches an operation handler.
+	validator *RequestValidator
+}
+
+// SpecialPaths returns the path patterns that require no authentication.
+// Backend declares no sudo paths, so the returned Root list is always empty.
+func (b *Backend) SpecialPaths() *logical.Paths {
+	return &logical.Paths{
+		Unauthenticated: b.Unauthenticated,
+	}
+}
+
+// HandleRequest is the single entry point every request to this backend goes
+// through: it routes req.Path to the matching Path, validates req.Data
+// against the generated OpenAPI document when EnableOpenAPIValidation has
+// been called, and then dispatches to the matched operation's handler.
+func (b *Backend) HandleRequest(ctx context.Context, req *logical.Request) (*logical.Response, error) {
+	p, bound := b.route(req.Path)
+	if p == nil {
+		return nil, logical.ErrUnsupportedPath
+	}
+
+	operations := p.Operations
+	if operations == nil {
+		operations = make(map[logical.Operation]OperationHandler)
+		for opType, cb := range p.Callbacks {
+			operations[opType] = &PathOperation{Callback: cb, Summary: p.HelpSynopsis}
+		}
+	}
+
+	opHandler, ok := operations[req.Operation]
+	if !ok {
+		return nil, logical.ErrUnsupportedOperation
+	}
+
+	if b.validator != nil {
+		oasPath, method := b.oasOperation(p, req.Operation)
+		if err := b.validator.ValidateRequest(oasPath, method, req.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.Data == nil {
+		req.Data = make(map[string]interface{})
+	}
+	for name, value := range bound {
+		if _, ok := req.Data[name]; !ok {
+			req.Data[name] = value
+		}
+	}
+
+	data := &FieldData{Raw: req.Data, Schema: p.Fields}
+
+	resp, err := opHandler.Handle(ctx, req, data)
+	if err != nil {
+		return resp, err
+	}
+
+	if b.validator != nil {
+		oasPath, method := b.oasOperation(p, req.Operation)
+		if err := b.validator.ValidateResponse(oasPath, method, responseCode(resp), responseData(resp)); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// responseCode returns the status code a response is validated under: a nil
+// or dataless response is a 204, everything else is a 200. This follows the
+// response itself, whereas documentPath's default responses follow the
+// operation type (204 for delete, 200 otherwise).
+func responseCode(resp *logical.Response) string {
+	if resp == nil || resp.Data == nil {
+		return "204"
+	}
+	return "200"
+}
+
+// responseData returns resp's data map, or an empty map for a nil response
+// so ValidateResponse always has something to range over.
+func responseData(resp *logical.Response) map[string]interface{} {
+	if resp == nil {
+		return map[string]interface{}{}
+	}
+	return resp.Data
+}
+
+// route finds the Path whose Pattern matches reqPath, returning it along
+// with its named path-segment captures.
+func (b *Backend) route(reqPath string) (*Path, map[string]interface{}) {
+	for _, p := range b.Paths {
+		re, err := regexp.Compile("^" + p.Pattern + "$")
+		if err != nil {
+			continue
+		}
+
+		match := re.FindStringSubmatch(reqPath)
+		if match == nil {
+			continue
+		}
+
+		bound := make(map[string]interface{})
+		for i, name := range re.SubexpNames() {
+			if i == 0 || name == "" {
+				continue
+			}
+			bound[name] = match[i]
+		}
+
+		return p, bound
+	}
+
+	return nil, nil
+}
+
+// oasOperation returns the OpenAPI document path and HTTP method that
+// correspond to p/op, for looking up the right schema in a RequestValidator.
+func (b *Backend) oasOperation(p *Path, op logical.Operation) (string, string) {
+	paths, _ := expandPattern(p.Pattern, p.Fields)
+	if len(paths) == 0 {
+		return "", ""
+	}
+
+	method := "POST"
+	switch op {
+	case logical.ReadOperation, logical.ListOperation:
+		method = "GET"
+	case logical.DeleteOperation:
+		method = "DELETE"
+	}
+
+	// A pattern with alternations or un-materialized multi-segment wildcards
+	// can expand to more than one documented path; the first is used as the
+	// representative schema since req.Path alone can't disambiguate further
+	// without re-matching each candidate.
+	return "/" + paths[0], method
+}